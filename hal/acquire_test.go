@@ -0,0 +1,80 @@
+package hal
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// TestAcquireConcurrentDuplicates confirms concurrent Acquire calls for the
+// same (roomId, broker, plugin) with no existing instance all converge on a
+// single shared *Instance rather than each registering their own.
+func TestAcquireConcurrentDuplicates(t *testing.T) {
+	p := &Plugin{Name: "acquire-concurrent-plugin"}
+	if err := p.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	pr := PluginRegistry()
+	broker := fakeBroker{"acquire-concurrent-broker"}
+
+	var wg sync.WaitGroup
+	results := make([]*Instance, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			inst, err := pr.Acquire("acquire-concurrent-room", broker, "acquire-concurrent-plugin")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[idx] = inst
+		}(i)
+	}
+	wg.Wait()
+
+	first := results[0]
+	for _, inst := range results {
+		if inst != first {
+			t.Fatalf("Acquire produced distinct instances for the same room/broker/plugin under concurrency: got %p and %p", first, inst)
+		}
+	}
+	if first.refCount != 20 {
+		t.Fatalf("refCount = %d, want 20", first.refCount)
+	}
+}
+
+// TestAcquireSkipsDrainingInstance confirms Acquire does not hand out a
+// reference to an instance that's already draining: a draining instance is
+// already excluded from InstanceList forever, so reusing it would give the
+// caller a reference that never receives events.
+func TestAcquireSkipsDrainingInstance(t *testing.T) {
+	p := &Plugin{Name: "acquire-draining-plugin"}
+	if err := p.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	pr := PluginRegistry()
+	broker := fakeBroker{"acquire-draining-broker"}
+
+	first, err := pr.Acquire("acquire-draining-room", broker, "acquire-draining-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := pr.Acquire("acquire-draining-room", broker, "acquire-draining-plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second == first {
+		t.Fatal("Acquire returned a draining instance instead of creating a fresh one")
+	}
+	if second.draining {
+		t.Fatal("Acquire returned an instance that is already draining")
+	}
+}
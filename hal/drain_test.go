@@ -0,0 +1,93 @@
+package hal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestDrainWaitsForInFlightDispatch confirms Drain does not return until a
+// dispatch that is already running Func has finished, even though the
+// dispatch's draining check and its wg.Add happened on a separate
+// goroutine from Drain's.
+func TestDrainWaitsForInFlightDispatch(t *testing.T) {
+	room := "drain-inflight-room"
+	broker := fakeBroker{"test"}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished bool
+
+	p := &Plugin{
+		Name: "drain-inflight-plugin",
+		Func: func(Evt) {
+			close(started)
+			<-release
+			finished = true
+		},
+	}
+	if err := p.Register(); err != nil {
+		t.Fatal(err)
+	}
+	inst := p.Instance(room, broker)
+	if err := inst.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	go PluginRegistry().Dispatch(Evt{})
+	<-started // Func is now running, wg is already bumped
+
+	drainDone := make(chan error, 1)
+	go func() {
+		drainDone <- inst.Drain(context.Background())
+	}()
+
+	select {
+	case <-drainDone:
+		t.Fatal("Drain returned before the in-flight dispatch finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+
+	if err := <-drainDone; err != nil {
+		t.Fatalf("Drain returned error: %v", err)
+	}
+	if !finished {
+		t.Fatal("Drain returned before Func set finished")
+	}
+}
+
+// TestDispatchSkipsDrainingInstance confirms an instance that has already
+// been drained is not handed evt at all, rather than racing to dispatch to
+// an instance that's in the middle of tearing down.
+func TestDispatchSkipsDrainingInstance(t *testing.T) {
+	room := "drain-skip-room"
+	broker := fakeBroker{"test"}
+
+	var called bool
+
+	p := &Plugin{
+		Name: "drain-skip-plugin",
+		Func: func(Evt) {
+			called = true
+		},
+	}
+	if err := p.Register(); err != nil {
+		t.Fatal(err)
+	}
+	inst := p.Instance(room, broker)
+	if err := inst.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := inst.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	PluginRegistry().Dispatch(Evt{})
+
+	if called {
+		t.Fatal("Dispatch ran Func on an instance that was already draining")
+	}
+}
@@ -0,0 +1,95 @@
+package hal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func echoFactory(calls *int) Factory {
+	return func(name string, config io.Reader) (*Plugin, error) {
+		*calls++
+		raw, err := io.ReadAll(config)
+		if err != nil {
+			return nil, err
+		}
+		return &Plugin{Name: name, Regex: string(raw)}, nil
+	}
+}
+
+func TestNewFromConfigBuildsThenReusesIdenticalConfig(t *testing.T) {
+	pr := PluginRegistry()
+	name := "factory-echo-plugin"
+
+	var calls int
+	pr.RegisterFactory(name, echoFactory(&calls))
+
+	first, err := pr.NewFromConfig(name, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("factory called %d times, want 1", calls)
+	}
+
+	second, err := pr.NewFromConfig(name, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Fatalf("NewFromConfig built a new Plugin for identical config instead of reusing the first")
+	}
+	if calls != 1 {
+		t.Fatalf("factory called %d times after a repeat call with identical config, want still 1", calls)
+	}
+}
+
+func TestNewFromConfigIdempotentAcrossCosmeticDifferences(t *testing.T) {
+	pr := PluginRegistry()
+	name := "factory-canonicalized-plugin"
+
+	var calls int
+	pr.RegisterFactory(name, echoFactory(&calls))
+
+	canonA, err := ReadYAMLConfig(strings.NewReader("key: value\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := pr.NewFromConfig(name, canonA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same content, but with a trailing blank line and CRLF endings -
+	// cosmetically different bytes that canonicalize to the same config
+	canonB, err := ReadYAMLConfig(strings.NewReader("key: value\r\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pr.NewFromConfig(name, canonB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second != first {
+		t.Fatal("NewFromConfig treated cosmetically-equivalent canonicalized config as different")
+	}
+	if calls != 1 {
+		t.Fatalf("factory called %d times, want 1", calls)
+	}
+}
+
+func TestNewFromConfigMissingFactory(t *testing.T) {
+	pr := PluginRegistry()
+
+	_, err := pr.NewFromConfig("factory-never-registered-plugin", strings.NewReader("anything"))
+	if err == nil {
+		t.Fatal("expected an error for a plugin name with no registered factory")
+	}
+
+	want := fmt.Sprintf("no factory registered for plugin: %q", "factory-never-registered-plugin")
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err.Error(), want)
+	}
+}
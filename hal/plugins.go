@@ -1,33 +1,76 @@
 package hal
 
 import (
+	"bytes"
+	"container/list"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"regexp"
+	"sort"
+	"strings"
 	"sync"
 )
 
 // pluginRegistry contains the plugin registration data as a singleton
 type pluginRegistry struct {
-	plugins   []*Plugin   // registered plugins
-	instances []*Instance // instances of plugins
-	mut       sync.Mutex  // concurrent access
-	init      sync.Once   // initialize the singleton once
+	plugins   map[string]*list.List  // registered plugins by name; front of each list is the current version
+	instances []*Instance            // instances of plugins
+	factories map[string]Factory     // factories registered by plugin name
+	configs   map[string]configEntry // last config seen per plugin name, for NewFromConfig idempotency
+	mut       sync.Mutex             // concurrent access
+	init      sync.Once              // initialize the singleton once
 }
 
+// Factory constructs a fully configured Plugin from a configuration
+// snippet, mirroring the Kubernetes admission controller Factory
+// pattern. Factories are registered once by plugin name and then used by
+// NewFromConfig to build a Plugin per config file found on disk.
+type Factory func(name string, config io.Reader) (*Plugin, error)
+
+// configEntry records the raw config bytes that produced a Plugin via
+// NewFromConfig, so a repeat call with the same config can return the
+// existing Plugin instead of constructing a new one.
+type configEntry struct {
+	raw    []byte
+	plugin *Plugin
+}
+
+// Decision is returned by a Plugin's HandleFunc to control how the
+// routing chain proceeds after the plugin has run.
+type Decision int
+
+const (
+	// Continue lets the routing chain proceed to the next instance.
+	Continue Decision = iota
+	// Stop halts the routing chain entirely for this event.
+	Stop
+	// Skip skips the remaining plugins in the same Group for this event,
+	// but otherwise lets the chain continue.
+	Skip
+)
+
 // Plugin is a function with metadata to assist with message routing.
 // Plugins are registered at startup by the main program and wired up
 // to receive events when an instance is created e.g. by the pluginmgr
 // plugin.
 // Most of the time only the Key field should be specified in Settings.
 type Plugin struct {
-	Name     string          // a unique name (used to launch instances)
-	Func     func(Evt)       // the code to execute for each matched event
-	Init     func(*Instance) // plugin hook called at instance creation time
-	Regex    string          // the default regex match
-	Settings Prefs           // required+autoloaded preferences + defaults
-	Secrets  []string        // required+autoloaded secret key names
+	Name         string             // a unique name (used to launch instances)
+	Func         func(Evt)          // the code to execute for each matched event
+	HandleFunc   func(Evt) Decision // like Func, but can control the routing chain
+	Init         func(*Instance)    // plugin hook called at instance creation time
+	Regex        string             // the default regex match
+	Settings     Prefs              // required+autoloaded preferences + defaults
+	Secrets      []string           // required+autoloaded secret key names
+	Priority     int                // routing chain order, ascending; ties are stable
+	Group        string             // named group a Skip decision short-circuits
+	Factory      Factory            // set on a Plugin built by NewFromConfig, records how it was built
+	Stop         func(*Instance)    // plugin hook called once an instance's last reference is released
+	Capabilities []string           // features this plugin offers, e.g. "reply", "upload", "react"
+	Version      string             // version string; multiple versions of a plugin may be registered at once
 }
 
 // Instance is an instance of a plugin tied to a room.
@@ -37,38 +80,148 @@ type Instance struct {
 	Broker   Broker         // the broker that produces events
 	Regex    string         // a regex for filtering messages
 	Settings Prefs          // runtime settings for the instance
+	Priority *int           // overrides Plugin.Priority when non-nil
 	regex    *regexp.Regexp // the compiled regex
+	refCount int            // number of acquirers sharing this instance
+	draining bool           // true once Drain has been called; excluded from InstanceList
+	wg       sync.WaitGroup // in-flight Func/HandleFunc calls, bumped by the router
+	mu       sync.RWMutex   // guards refCount and draining
+}
+
+// HasCapability reports whether the instance's plugin declares cap among
+// its Capabilities, so callers can check before Acquire that a plugin
+// provides what they need.
+func (inst *Instance) HasCapability(cap string) bool {
+	for _, c := range inst.Plugin.Capabilities {
+		if c == cap {
+			return true
+		}
+	}
+
+	return false
+}
+
+// priority returns the instance's effective routing priority: its own
+// override if set, otherwise the plugin's default.
+func (inst *Instance) priority() int {
+	if inst.Priority != nil {
+		return *inst.Priority
+	}
+
+	return inst.Plugin.Priority
 }
 
 var pluginRegSingleton pluginRegistry
 
 func PluginRegistry() *pluginRegistry {
 	pluginRegSingleton.init.Do(func() {
-		pluginRegSingleton.plugins = make([]*Plugin, 0)
+		pluginRegSingleton.plugins = make(map[string]*list.List)
 		pluginRegSingleton.instances = make([]*Instance, 0)
+		pluginRegSingleton.factories = make(map[string]Factory)
+		pluginRegSingleton.configs = make(map[string]configEntry)
 	})
 
 	return &pluginRegSingleton
 }
 
-// Register registers a plugin with the bot.
+// Register registers a plugin with the bot. Registering a name that
+// already has a plugin with a different Version supersedes it: the new
+// version becomes current (returned by GetPlugin) while the superseded
+// version stays reachable via GetPluginVersion for any instances still
+// bound to it.
 func (p *Plugin) Register() error {
 	pr := PluginRegistry()
 	pr.mut.Lock()
 	defer pr.mut.Unlock()
 
-	for _, plugin := range pr.plugins {
-		if plugin.Name == p.Name {
-			log.Printf("Ignoring multiple calls to Register() for plugin '%s'", p.Name)
+	versions, ok := pr.plugins[p.Name]
+	if !ok {
+		versions = list.New()
+		pr.plugins[p.Name] = versions
+	}
+
+	for e := versions.Front(); e != nil; e = e.Next() {
+		if e.Value.(*Plugin).Version == p.Version {
+			log.Printf("Ignoring multiple calls to Register() for plugin '%s' version %q", p.Name, p.Version)
 			return nil
 		}
 	}
 
-	pr.plugins = append(pr.plugins, p)
+	versions.PushFront(p)
 
 	return nil
 }
 
+// RegisterFactory registers a Factory for the given plugin name so that
+// NewFromConfig can later build a Plugin from an on-disk config snippet
+// without any code changes to main. Re-registering the same name
+// replaces the previous factory.
+func (pr *pluginRegistry) RegisterFactory(name string, f Factory) {
+	pr.mut.Lock()
+	defer pr.mut.Unlock()
+
+	pr.factories[name] = f
+}
+
+// NewFromConfig builds a Plugin for name using its registered Factory and
+// cfg as the configuration snippet. It is idempotent: calling it again
+// with byte-identical config for the same name returns the Plugin built
+// the first time rather than constructing a new one. It does not
+// Register the Plugin; that is left to the caller.
+func (pr *pluginRegistry) NewFromConfig(name string, cfg io.Reader) (*Plugin, error) {
+	raw, err := io.ReadAll(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pr.mut.Lock()
+	defer pr.mut.Unlock()
+
+	if entry, ok := pr.configs[name]; ok && bytes.Equal(entry.raw, raw) {
+		return entry.plugin, nil
+	}
+
+	f, ok := pr.factories[name]
+	if !ok {
+		return nil, fmt.Errorf("no factory registered for plugin: %q", name)
+	}
+
+	p, err := f(name, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	p.Factory = f
+
+	pr.configs[name] = configEntry{raw: raw, plugin: p}
+
+	return p, nil
+}
+
+// ReadYAMLConfig canonicalizes a YAML configuration snippet (normalizing
+// line endings and surrounding whitespace) so that cosmetic differences
+// don't defeat the idempotency check in NewFromConfig. It does not parse
+// the YAML itself; that's left to the plugin's Factory.
+func ReadYAMLConfig(r io.Reader) (io.Reader, error) {
+	return canonicalizeConfig(r)
+}
+
+// ReadTOMLConfig is the TOML equivalent of ReadYAMLConfig.
+func ReadTOMLConfig(r io.Reader) (io.Reader, error) {
+	return canonicalizeConfig(r)
+}
+
+func canonicalizeConfig(r io.Reader) (io.Reader, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	raw = bytes.TrimSpace(raw)
+
+	return bytes.NewReader(raw), nil
+}
+
 // Instance creates an instance of a plugin. It is *not* registered (and
 // therefore not considered by the router until that is done).
 func (p *Plugin) Instance(roomId string, broker Broker) *Instance {
@@ -88,6 +241,14 @@ func (inst *Instance) Register() error {
 	pr.mut.Lock()
 	defer pr.mut.Unlock()
 
+	return inst.registerLocked(pr)
+}
+
+// registerLocked does the work of Register, assuming pr.mut is already
+// held. It exists so callers that need to check-then-register under a
+// single critical section (e.g. Acquire) can do so without recursively
+// locking pr.mut.
+func (inst *Instance) registerLocked(pr *pluginRegistry) error {
 	// default to the plugin's default if no RE was provided
 	if inst.Regex == "" {
 		inst.Regex = inst.Plugin.Regex
@@ -102,9 +263,23 @@ func (inst *Instance) Register() error {
 		inst.Plugin.Init(inst)
 	}
 
+	// a plain Register() (not going through Acquire) is a single owner,
+	// so it starts with a reference count of 1; Acquire bumps it further
+	// for additional consumers of the same instance
+	inst.mu.Lock()
+	if inst.refCount == 0 {
+		inst.refCount = 1
+	}
+	inst.mu.Unlock()
+
 	// once an instance is registered, the router will automatically
-	// pick it up on the next message it processes
+	// pick it up on the next message it processes. Keep the slice sorted
+	// by priority (ascending) so the router never has to sort on the hot
+	// path; sort.SliceStable preserves registration order for ties.
 	pr.instances = append(pr.instances, inst)
+	sort.SliceStable(pr.instances, func(i, j int) bool {
+		return pr.instances[i].priority() < pr.instances[j].priority()
+	})
 
 	log.Printf("Registered plugin %q in room id %q on broker %q with RE match %q",
 		inst.Name, inst.RoomId, inst.Broker.Name(), inst.regex)
@@ -112,13 +287,25 @@ func (inst *Instance) Register() error {
 	return nil
 }
 
-// Unregister removes an instance from the list of plugin instances.
+// Unregister removes an instance from the list of plugin instances. If
+// the instance is shared (its reference count was bumped by Acquire),
+// this only drops one reference; the instance is actually removed once
+// the last reference is released.
 func (inst *Instance) Unregister() error {
 	pr := PluginRegistry()
 	pr.mut.Lock()
-	defer pr.mut.Unlock()
 
-	var idx int
+	inst.mu.Lock()
+	inst.refCount--
+	rc := inst.refCount
+	inst.mu.Unlock()
+
+	if rc > 0 {
+		pr.mut.Unlock()
+		return nil
+	}
+
+	idx := -1
 	for j, i := range pr.instances {
 		// TODO: verify if pointer equality is sufficient
 		if i == inst {
@@ -127,14 +314,108 @@ func (inst *Instance) Unregister() error {
 		}
 	}
 
+	// Not found, e.g. a double Release on an already-unregistered instance:
+	// there's nothing to splice out, and Stop must not run twice.
+	if idx == -1 {
+		pr.mut.Unlock()
+		return nil
+	}
+
 	// delete the instance from the list
 	pr.instances = append(pr.instances[:idx], pr.instances[idx+1:]...)
 
+	// Stop is called after pr.mut is released, not before: it may itself
+	// touch the registry (e.g. to Acquire a replacement), and calling it
+	// while still holding pr.mut would deadlock.
+	pr.mut.Unlock()
+
+	if inst.Plugin.Stop != nil {
+		inst.Plugin.Stop(inst)
+	}
+
 	log.Printf("Unregistered plugin '%s' from room id '%s'", inst.Name, inst.RoomId)
 
 	return nil
 }
 
+// Acquire returns the existing instance of plugin for roomId/broker,
+// bumping its reference count, or creates, registers, and returns a new
+// one with a reference count of 1. This lets multiple consumers share a
+// single plugin instance and, via Release, know when the last consumer
+// is gone so the plugin can tear down. An existing instance is reused
+// only when roomId, broker name and plugin name all match; a different
+// room or broker gets its own instance.
+//
+// The existing-instance check and the create-and-register fallback run
+// under a single hold of pr.mut so two concurrent Acquire calls for the
+// same (roomId, broker, plugin) can't both miss the check and each
+// register their own instance.
+func (pr *pluginRegistry) Acquire(roomId string, broker Broker, plugin string) (*Instance, error) {
+	pr.mut.Lock()
+	defer pr.mut.Unlock()
+
+	for _, i := range pr.instances {
+		if i.Plugin.Name == plugin && i.Broker.Name() == broker.Name() && i.RoomId == roomId {
+			i.mu.Lock()
+			if i.draining {
+				// this instance is on its way out and already excluded from
+				// InstanceList; treat it as not found so a fresh instance
+				// gets created instead of handing back a reference that
+				// will never receive events
+				i.mu.Unlock()
+				continue
+			}
+			i.refCount++
+			i.mu.Unlock()
+			return i, nil
+		}
+	}
+
+	p, err := pr.getPluginLocked(plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	inst := p.Instance(roomId, broker)
+	if err := inst.registerLocked(pr); err != nil {
+		return nil, err
+	}
+
+	return inst, nil
+}
+
+// Release drops this consumer's reference to the instance. Once the last
+// reference is released the instance is unregistered, which runs the
+// plugin's Stop hook (if any).
+func (inst *Instance) Release() error {
+	return inst.Unregister()
+}
+
+// Drain marks the instance so InstanceList stops offering it new events,
+// then blocks until every Func/HandleFunc call the router already
+// dispatched to it has finished, or ctx is canceled. It gives long-running
+// plugins (timers, webhooks, pollers) a clean shutdown path; callers
+// typically Drain an instance before Unregister/Release so Stop doesn't
+// run out from under an in-flight call.
+func (inst *Instance) Drain(ctx context.Context) error {
+	inst.mu.Lock()
+	inst.draining = true
+	inst.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		inst.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // LoadSettingsFromPrefs loads all of the settings specified in the plugin
 // Settings list into the instance's Settings list. Any current settings
 // are replaced. The search is run with room and plugin set to whatever
@@ -170,17 +451,23 @@ func (inst *Instance) SaveSettingsToPrefs() {
 	}
 }
 
-// PluginList returns a snapshot of the plugin list at call time.
+// PluginList returns a snapshot of the current version of every
+// registered plugin at call time.
 func (pr *pluginRegistry) PluginList() []*Plugin {
 	pr.mut.Lock()
 	defer pr.mut.Unlock()
 
-	out := make([]*Plugin, len(pr.plugins))
-	copy(out, pr.plugins) // intentional shallow copy
+	out := make([]*Plugin, 0, len(pr.plugins))
+	for _, versions := range pr.plugins {
+		if versions.Len() > 0 {
+			out = append(out, versions.Front().Value.(*Plugin))
+		}
+	}
 	return out
 }
 
-// InstanceList returns a snapshot of the instance list at call time.
+// InstanceList returns a snapshot of the instance list at call time,
+// excluding any instance currently draining (see Instance.Drain).
 func (pr *pluginRegistry) InstanceList() []*Instance {
 	pr.mut.Lock()
 	defer pr.mut.Unlock()
@@ -189,23 +476,242 @@ func (pr *pluginRegistry) InstanceList() []*Instance {
 	// might come to pass that this will perform poorly, but for now with a
 	// relatively small number of instances we'll take the copy hit in exchange
 	// for not having to think about concurrent access to the list
-	out := make([]*Instance, len(pr.instances))
-	copy(out, pr.instances) // intentional shallow copy
+	out := make([]*Instance, 0, len(pr.instances))
+	for _, i := range pr.instances {
+		i.mu.RLock()
+		draining := i.draining
+		i.mu.RUnlock()
+
+		if !draining {
+			out = append(out, i)
+		}
+	}
 	return out
 }
 
-// GetPlugin returns the plugin specified by its name string.
+// RoutingChain returns the instances that should be offered evt, in
+// priority order (ascending). The slice is already sorted as a side
+// effect of how Register maintains pr.instances, so this is currently
+// just a documented alias for InstanceList, but callers should prefer it
+// over InstanceList when the intent is to walk the routing chain.
+func (pr *pluginRegistry) RoutingChain(evt Evt) []*Instance {
+	return pr.InstanceList()
+}
+
+// beginDispatch checks inst.draining and, if it's false, bumps inst.wg,
+// atomically under inst.mu. It reports whether the dispatch should
+// proceed. This closes a race between Dispatch and Drain: RoutingChain's
+// snapshot can go stale between being taken and the wg.Add that follows
+// it, so checking draining and calling wg.Add must happen as one step,
+// not against a pre-fetched draining value.
+func (inst *Instance) beginDispatch() bool {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	if inst.draining {
+		return false
+	}
+
+	inst.wg.Add(1)
+
+	return true
+}
+
+// Dispatch runs evt through the routing chain in priority order. Each
+// instance's HandleFunc is called if present; otherwise Func is called
+// and treated as Continue. A Stop decision halts the chain for this
+// event. A Skip decision skips the remaining instances in the same
+// Group, but otherwise lets the chain continue. Each instance's wg is
+// bumped for the duration of its call so Drain can wait for in-flight
+// dispatches to finish; an instance that started draining between the
+// routing snapshot and its turn here is skipped instead of dispatched.
+func (pr *pluginRegistry) Dispatch(evt Evt) {
+	skipGroups := make(map[string]bool)
+
+	for _, inst := range pr.RoutingChain(evt) {
+		if inst.Plugin.Group != "" && skipGroups[inst.Plugin.Group] {
+			continue
+		}
+
+		if !inst.beginDispatch() {
+			continue
+		}
+
+		decision := func() Decision {
+			defer inst.wg.Done()
+
+			switch {
+			case inst.Plugin.HandleFunc != nil:
+				return inst.Plugin.HandleFunc(evt)
+			case inst.Plugin.Func != nil:
+				inst.Plugin.Func(evt)
+			}
+
+			return Continue
+		}()
+
+		switch decision {
+		case Stop:
+			return
+		case Skip:
+			if inst.Plugin.Group != "" {
+				skipGroups[inst.Plugin.Group] = true
+			}
+		}
+	}
+}
+
+// GetPlugin returns the current (most recently registered) version of
+// the plugin specified by its name string.
 func (pr *pluginRegistry) GetPlugin(name string) (*Plugin, error) {
 	pr.mut.Lock()
 	defer pr.mut.Unlock()
 
-	for _, p := range pr.plugins {
-		if p.Name == name {
+	return pr.getPluginLocked(name)
+}
+
+// getPluginLocked does the work of GetPlugin, assuming pr.mut is already
+// held.
+func (pr *pluginRegistry) getPluginLocked(name string) (*Plugin, error) {
+	versions, ok := pr.plugins[name]
+	if !ok || versions.Front() == nil {
+		return nil, errors.New(fmt.Sprintf("no such plugin: %q", name))
+	}
+
+	return versions.Front().Value.(*Plugin), nil
+}
+
+// GetPluginVersion returns the specific version of the named plugin,
+// whether or not it is the current version.
+func (pr *pluginRegistry) GetPluginVersion(name, version string) (*Plugin, error) {
+	pr.mut.Lock()
+	defer pr.mut.Unlock()
+
+	versions, ok := pr.plugins[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("no such plugin: %q", name))
+	}
+
+	for e := versions.Front(); e != nil; e = e.Next() {
+		if p := e.Value.(*Plugin); p.Version == version {
 			return p, nil
 		}
 	}
 
-	return nil, errors.New(fmt.Sprintf("no such plugin: %q", name))
+	return nil, errors.New(fmt.Sprintf("no such plugin version: %q %q", name, version))
+}
+
+// DeregisterPlugin removes a specific version of a plugin. If it was the
+// current version, the next-most-recently-registered version (if any)
+// becomes current. Instances already bound to the removed version keep
+// their direct *Plugin pointer and are unaffected.
+func (pr *pluginRegistry) DeregisterPlugin(name, version string) error {
+	pr.mut.Lock()
+	defer pr.mut.Unlock()
+
+	versions, ok := pr.plugins[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("no such plugin: %q", name))
+	}
+
+	for e := versions.Front(); e != nil; e = e.Next() {
+		if e.Value.(*Plugin).Version != version {
+			continue
+		}
+
+		versions.Remove(e)
+		if versions.Len() == 0 {
+			delete(pr.plugins, name)
+		}
+
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf("no such plugin version: %q %q", name, version))
+}
+
+// ErrNotFound is returned by ResolvePlugin/ResolveInstance when nothing
+// matches the given reference or prefix.
+var ErrNotFound = errors.New("no matching plugin")
+
+// ErrAmbiguous is returned by ResolvePlugin/ResolveInstance when a
+// prefix matches more than one candidate; use errors.Is to detect it.
+var ErrAmbiguous = errors.New("ambiguous plugin reference")
+
+// ResolvePlugin resolves refOrPrefix to a single current plugin version,
+// Docker-plugin-store style: an exact name match wins outright,
+// otherwise refOrPrefix must be a unique case-insensitive prefix of
+// exactly one registered plugin name.
+func (pr *pluginRegistry) ResolvePlugin(refOrPrefix string) (*Plugin, error) {
+	pr.mut.Lock()
+	defer pr.mut.Unlock()
+
+	if versions, ok := pr.plugins[refOrPrefix]; ok && versions.Len() > 0 {
+		return versions.Front().Value.(*Plugin), nil
+	}
+
+	ref := strings.ToLower(refOrPrefix)
+
+	var candidates []string
+	for name, versions := range pr.plugins {
+		if versions.Len() > 0 && strings.HasPrefix(strings.ToLower(name), ref) {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, refOrPrefix)
+	case 1:
+		return pr.plugins[candidates[0]].Front().Value.(*Plugin), nil
+	default:
+		return nil, fmt.Errorf("%w: %q matches %s", ErrAmbiguous, refOrPrefix, strings.Join(candidates, ", "))
+	}
+}
+
+// ResolveInstance resolves brokerPrefix and pluginPrefix, within roomId,
+// to a single instance using the same exact-then-unique-prefix rules as
+// ResolvePlugin, case-insensitively and with stable (sorted) candidate
+// ordering in the ErrAmbiguous error.
+func (pr *pluginRegistry) ResolveInstance(roomId, brokerPrefix, pluginPrefix string) (*Instance, error) {
+	pr.mut.Lock()
+	defer pr.mut.Unlock()
+
+	for _, i := range pr.instances {
+		if i.RoomId == roomId && i.Broker.Name() == brokerPrefix && i.Plugin.Name == pluginPrefix {
+			return i, nil
+		}
+	}
+
+	lb := strings.ToLower(brokerPrefix)
+	lp := strings.ToLower(pluginPrefix)
+
+	var candidates []*Instance
+	var labels []string
+	for _, i := range pr.instances {
+		if i.RoomId != roomId {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(i.Broker.Name()), lb) && strings.HasPrefix(strings.ToLower(i.Plugin.Name), lp) {
+			candidates = append(candidates, i)
+			// Instance.String() omits the broker, so two instances of the
+			// same plugin in the same room that differ only by broker
+			// (exactly what brokerPrefix exists to disambiguate) would
+			// otherwise produce identical, useless labels.
+			labels = append(labels, fmt.Sprintf("%s/%s/%s", i.Broker.Name(), i.Plugin.Name, i.RoomId))
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%w: %q/%q in room %q", ErrNotFound, brokerPrefix, pluginPrefix, roomId)
+	case 1:
+		return candidates[0], nil
+	default:
+		sort.Strings(labels)
+		return nil, fmt.Errorf("%w: %q/%q in room %q matches %s", ErrAmbiguous, brokerPrefix, pluginPrefix, roomId, strings.Join(labels, ", "))
+	}
 }
 
 // FindInstances returns the plugin instances that match the provided
@@ -0,0 +1,42 @@
+package hal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestResolveInstanceAmbiguousLabelsDistinguishBroker confirms that two
+// instances of the same plugin in the same room, differing only by
+// broker, produce distinct labels in the ErrAmbiguous message instead of
+// the broker-less duplicate that Instance.String() would give.
+func TestResolveInstanceAmbiguousLabelsDistinguishBroker(t *testing.T) {
+	p := &Plugin{Name: "resolve-ambiguous-plugin"}
+	if err := p.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	pr := PluginRegistry()
+	room := "resolve-ambiguous-room"
+
+	slack := p.Instance(room, fakeBroker{"slack"})
+	if err := slack.Register(); err != nil {
+		t.Fatal(err)
+	}
+	irc := p.Instance(room, fakeBroker{"irc"})
+	if err := irc.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := pr.ResolveInstance(room, "", "resolve-ambiguous")
+	if !errors.Is(err, ErrAmbiguous) {
+		t.Fatalf("expected ErrAmbiguous, got %v", err)
+	}
+
+	wantIrc := "irc/resolve-ambiguous-plugin/" + room
+	wantSlack := "slack/resolve-ambiguous-plugin/" + room
+	msg := err.Error()
+	if !strings.Contains(msg, wantIrc) || !strings.Contains(msg, wantSlack) {
+		t.Fatalf("error %q does not contain distinct broker-qualified labels %q and %q", msg, wantIrc, wantSlack)
+	}
+}
@@ -0,0 +1,165 @@
+package hal
+
+import "testing"
+
+// fakeBroker is a minimal Broker for tests that only need a name.
+type fakeBroker struct{ name string }
+
+func (f fakeBroker) Name() string { return f.name }
+
+func pluginNames(instances []*Instance, prefix string) []string {
+	var names []string
+	for _, inst := range instances {
+		if len(inst.Plugin.Name) >= len(prefix) && inst.Plugin.Name[:len(prefix)] == prefix {
+			names = append(names, inst.Plugin.Name)
+		}
+	}
+	return names
+}
+
+func equalNames(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRoutingChainPriorityOrder(t *testing.T) {
+	room := "routing-priority-room"
+	broker := fakeBroker{"test"}
+
+	high := &Plugin{Name: "routing-priority-high", Priority: 300}
+	low := &Plugin{Name: "routing-priority-low", Priority: 100}
+	mid := &Plugin{Name: "routing-priority-mid", Priority: 200}
+
+	// register out of priority order to prove the chain follows priority, not registration order
+	for _, p := range []*Plugin{high, low, mid} {
+		if err := p.Register(); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Instance(room, broker).Register(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := pluginNames(PluginRegistry().RoutingChain(Evt{}), "routing-priority-")
+	want := []string{"routing-priority-low", "routing-priority-mid", "routing-priority-high"}
+	if !equalNames(got, want) {
+		t.Fatalf("RoutingChain order = %v, want %v", got, want)
+	}
+}
+
+func TestRoutingChainStableTies(t *testing.T) {
+	room := "routing-ties-room"
+	broker := fakeBroker{"test"}
+
+	first := &Plugin{Name: "routing-ties-first"}
+	second := &Plugin{Name: "routing-ties-second"}
+
+	for _, p := range []*Plugin{first, second} {
+		if err := p.Register(); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Instance(room, broker).Register(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := pluginNames(PluginRegistry().RoutingChain(Evt{}), "routing-ties-")
+	want := []string{"routing-ties-first", "routing-ties-second"}
+	if !equalNames(got, want) {
+		t.Fatalf("RoutingChain did not preserve registration order for equal priority: got %v, want %v", got, want)
+	}
+}
+
+func TestDispatchGroupSkip(t *testing.T) {
+	room := "routing-group-room"
+	broker := fakeBroker{"test"}
+
+	var called []string
+
+	deny := &Plugin{
+		Name:     "routing-group-deny",
+		Group:    "routing-group-auth",
+		Priority: 0,
+		HandleFunc: func(Evt) Decision {
+			called = append(called, "deny")
+			return Skip
+		},
+	}
+	sibling := &Plugin{
+		Name:     "routing-group-sibling",
+		Group:    "routing-group-auth",
+		Priority: 1,
+		Func: func(Evt) {
+			called = append(called, "sibling")
+		},
+	}
+	unrelated := &Plugin{
+		Name:     "routing-group-unrelated",
+		Priority: 2,
+		Func: func(Evt) {
+			called = append(called, "unrelated")
+		},
+	}
+
+	for _, p := range []*Plugin{deny, sibling, unrelated} {
+		if err := p.Register(); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Instance(room, broker).Register(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	PluginRegistry().Dispatch(Evt{})
+
+	want := []string{"deny", "unrelated"}
+	if !equalNames(called, want) {
+		t.Fatalf("Dispatch called %v, want %v (sibling should be skipped via the Skip group)", called, want)
+	}
+}
+
+func TestDispatchStop(t *testing.T) {
+	room := "routing-stop-room"
+	broker := fakeBroker{"test"}
+
+	var called []string
+
+	halt := &Plugin{
+		Name:     "routing-stop-halt",
+		Priority: 0,
+		HandleFunc: func(Evt) Decision {
+			called = append(called, "halt")
+			return Stop
+		},
+	}
+	after := &Plugin{
+		Name:     "routing-stop-after",
+		Priority: 1,
+		Func: func(Evt) {
+			called = append(called, "after")
+		},
+	}
+
+	for _, p := range []*Plugin{halt, after} {
+		if err := p.Register(); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Instance(room, broker).Register(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	PluginRegistry().Dispatch(Evt{})
+
+	want := []string{"halt"}
+	if !equalNames(called, want) {
+		t.Fatalf("Dispatch called %v, want %v (chain should halt at the Stop decision)", called, want)
+	}
+}
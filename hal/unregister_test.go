@@ -0,0 +1,56 @@
+package hal
+
+import "testing"
+
+// TestReleaseTwiceDoesNotStopUnrelatedInstance guards against a caller bug
+// (e.g. a double Release) unregistering an instance that's already gone
+// from pr.instances: it must be a no-op, not splice out and Stop whatever
+// instance happens to be at index 0.
+func TestReleaseTwiceDoesNotStopUnrelatedInstance(t *testing.T) {
+	room := "unregister-double-room"
+	broker := fakeBroker{"test"}
+
+	var victimStopped bool
+	victim := &Plugin{
+		Name: "unregister-double-victim",
+		Stop: func(*Instance) {
+			victimStopped = true
+		},
+	}
+	if err := victim.Register(); err != nil {
+		t.Fatal(err)
+	}
+	victimInst := victim.Instance(room, broker)
+	if err := victimInst.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	var doubleStops int
+	double := &Plugin{
+		Name: "unregister-double-target",
+		Stop: func(*Instance) {
+			doubleStops++
+		},
+	}
+	if err := double.Register(); err != nil {
+		t.Fatal(err)
+	}
+	doubleInst := double.Instance(room, broker)
+	if err := doubleInst.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doubleInst.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := doubleInst.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if doubleStops != 1 {
+		t.Fatalf("Stop called %d times for the double-released instance, want 1", doubleStops)
+	}
+	if victimStopped {
+		t.Fatal("double Release ran Stop on an unrelated instance")
+	}
+}
@@ -0,0 +1,101 @@
+package hal
+
+import "testing"
+
+func TestPluginSupersedeWithLiveInstances(t *testing.T) {
+	room := "versions-room"
+	broker := fakeBroker{"test"}
+
+	v1 := &Plugin{Name: "versions-echo", Version: "v1"}
+	if err := v1.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	inst1 := v1.Instance(room, broker)
+	if err := inst1.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	v2 := &Plugin{Name: "versions-echo", Version: "v2"}
+	if err := v2.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	pr := PluginRegistry()
+
+	current, err := pr.GetPlugin("versions-echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != v2 {
+		t.Fatalf("GetPlugin returned version %q, want the v2 just registered", current.Version)
+	}
+
+	// inst1 was bound to v1 at Instance() time and keeps that direct
+	// pointer; registering v2 must not disrupt it
+	if inst1.Plugin != v1 {
+		t.Fatalf("inst1 was rebound to version %q after v2 superseded it, want to keep v1", inst1.Plugin.Version)
+	}
+
+	// v1 is superseded but still reachable by version for any live instances
+	oldVersion, err := pr.GetPluginVersion("versions-echo", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldVersion != v1 {
+		t.Fatal("GetPluginVersion(v1) did not return the original v1 plugin")
+	}
+
+	// a new instance created against the current plugin binds to v2
+	inst2 := current.Instance(room, broker)
+	if err := inst2.Register(); err != nil {
+		t.Fatal(err)
+	}
+	if inst2.Plugin != v2 {
+		t.Fatalf("new instance bound to version %q, want v2", inst2.Plugin.Version)
+	}
+
+	// deregistering the superseded v1 doesn't disturb v2 or inst1, which
+	// still holds its own *Plugin pointer
+	if err := pr.DeregisterPlugin("versions-echo", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if inst1.Plugin != v1 {
+		t.Fatal("inst1 lost its v1 binding after DeregisterPlugin")
+	}
+	if _, err := pr.GetPluginVersion("versions-echo", "v1"); err == nil {
+		t.Fatal("expected v1 to be gone after DeregisterPlugin")
+	}
+
+	current, err = pr.GetPlugin("versions-echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != v2 {
+		t.Fatalf("GetPlugin returned %q after deregistering v1, want v2", current.Version)
+	}
+}
+
+func TestDeregisterPluginPromotesNextVersion(t *testing.T) {
+	v1 := &Plugin{Name: "versions-promote", Version: "v1"}
+	v2 := &Plugin{Name: "versions-promote", Version: "v2"}
+	if err := v1.Register(); err != nil {
+		t.Fatal(err)
+	}
+	if err := v2.Register(); err != nil {
+		t.Fatal(err)
+	}
+
+	pr := PluginRegistry()
+	if err := pr.DeregisterPlugin("versions-promote", "v2"); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := pr.GetPlugin("versions-promote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != v1 {
+		t.Fatalf("got current version %q, want v1 promoted after v2 was deregistered", current.Version)
+	}
+}